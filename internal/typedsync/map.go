@@ -0,0 +1,60 @@
+// Package typedsync provides a generic, type-safe wrapper around sync.Map,
+// so that callers need not write the interface{} conversions and type
+// assertions that come with using sync.Map directly; the wrapper performs
+// them internally.
+package typedsync
+
+import "sync"
+
+// Map is a type-safe wrapper around sync.Map
+type Map[K comparable, V any] struct {
+	m sync.Map
+}
+
+// Load returns the value stored for key, and whether it was present
+func (m *Map[K, V]) Load(key K) (V, bool) {
+	v, ok := m.m.Load(key)
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	return v.(V), true
+}
+
+// Store sets the value for key
+func (m *Map[K, V]) Store(key K, value V) {
+	m.m.Store(key, value)
+}
+
+// LoadOrStore returns the existing value for key if present. Otherwise, it
+// stores and returns value. The loaded result is true if value was loaded,
+// false if stored.
+func (m *Map[K, V]) LoadOrStore(key K, value V) (V, bool) {
+	actual, loaded := m.m.LoadOrStore(key, value)
+	return actual.(V), loaded
+}
+
+// Delete removes the value for key
+func (m *Map[K, V]) Delete(key K) {
+	m.m.Delete(key)
+}
+
+// Range calls f sequentially for each key and value present in the map. If
+// f returns false, Range stops the iteration. Range follows the same
+// no-particular-order and concurrent-mutation semantics as sync.Map.Range.
+func (m *Map[K, V]) Range(f func(key K, value V) bool) {
+	m.m.Range(func(k, v interface{}) bool {
+		return f(k.(K), v.(V))
+	})
+}
+
+// CompareAndSwap swaps the old and new values for key if the value stored in
+// the map is equal to old
+func (m *Map[K, V]) CompareAndSwap(key K, old, new V) bool {
+	return m.m.CompareAndSwap(key, old, new)
+}
+
+// CompareAndDelete deletes the entry for key if its value is equal to old
+func (m *Map[K, V]) CompareAndDelete(key K, old V) bool {
+	return m.m.CompareAndDelete(key, old)
+}
@@ -1,17 +1,23 @@
 package slicemap
 
 import (
+	"container/heap"
+	"iter"
 	"slices"
 	"sort"
-	"sync"
 
 	"golang.org/x/exp/constraints"
+
+	"goupdate/slicemap/internal/typedsync"
 )
 
-// SliceMap is a map of slices of ordered values
+// SliceMap is a map of slices of ordered values. Each key's slice is treated
+// as immutable: every mutation builds a new sorted slice and swaps it in
+// with LoadOrStore (for a new key) or CompareAndSwap/CompareAndDelete,
+// retrying on conflict. This means
+// operations on different keys never contend with each other.
 type SliceMap[K constraints.Ordered, V constraints.Ordered] struct {
-	sync.RWMutex
-	data sync.Map
+	data typedsync.Map[K, *[]V]
 }
 
 // NewSliceMap creates a new SliceMap
@@ -19,79 +25,99 @@ func NewSliceMap[K, V constraints.Ordered]() *SliceMap[K, V] {
 	return &SliceMap[K, V]{}
 }
 
+// insertSorted returns a new sorted slice with value inserted, or nil if
+// value is already present
+func insertSorted[V constraints.Ordered](slice []V, value V) []V {
+	if len(slice) > 0 {
+		if value < slice[0] {
+			ns := make([]V, len(slice)+1)
+			ns[0] = value
+			copy(ns[1:], slice)
+			return ns
+		}
+		if value > slice[len(slice)-1] {
+			ns := make([]V, len(slice)+1)
+			copy(ns, slice)
+			ns[len(slice)] = value
+			return ns
+		}
+		if slice[0] == value || slice[len(slice)-1] == value {
+			return nil // Value already exists
+		}
+	}
+
+	i := sort.Search(len(slice), func(i int) bool { return slice[i] >= value })
+	if i < len(slice) && slice[i] == value {
+		return nil // Value already exists
+	}
+	ns := make([]V, len(slice)+1)
+	copy(ns, slice[:i])
+	ns[i] = value
+	copy(ns[i+1:], slice[i:])
+	return ns
+}
+
+// deleteSorted returns a new sorted slice with value removed, and whether it
+// was present. slice is not mutated.
+func deleteSorted[V constraints.Ordered](slice []V, value V) ([]V, bool) {
+	if len(slice) == 0 || value < slice[0] || value > slice[len(slice)-1] {
+		return slice, false
+	}
+
+	i := sort.Search(len(slice), func(i int) bool { return slice[i] >= value })
+	if i >= len(slice) || slice[i] != value {
+		return slice, false
+	}
+	ns := make([]V, len(slice)-1)
+	copy(ns, slice[:i])
+	copy(ns[i:], slice[i+1:])
+	return ns, true
+}
+
 // Add adds a value to the slice associated with the given key
 func (sm *SliceMap[K, V]) Add(key K, value V) {
-	sm.Lock()
-	defer sm.Unlock()
-
-	if slice_, ok := sm.data.Load(key); ok {
-		slice := slice_.(*[]V)
-		if len(*slice) > 0 {
-			if value < (*slice)[0] {
-				// Insert at the beginning
-				*slice = append([]V{value}, *slice...)
-				return
-			} else if value > (*slice)[len(*slice)-1] {
-				// Insert at the end
-				*slice = append(*slice, value)
+	for {
+		old, ok := sm.data.Load(key)
+		if !ok {
+			ns := []V{value}
+			if _, loaded := sm.data.LoadOrStore(key, &ns); !loaded {
 				return
 			}
-
-			if (*slice)[0] == value || (*slice)[len(*slice)-1] == value {
-				return // Value already exists
-			}
+			continue // Someone else created the key first; retry against it
 		}
 
-		// Binary search to find the insertion point
-		i := sort.Search(len(*slice), func(i int) bool { return (*slice)[i] >= value })
-		if i < len(*slice) && (*slice)[i] == value {
+		ns := insertSorted(*old, value)
+		if ns == nil {
 			return // Value already exists
 		}
-		// Insert value at the index found
-		*slice = append(*slice, value)
-		copy((*slice)[i+1:], (*slice)[i:])
-		(*slice)[i] = value
-	} else {
-		// Create a new slice and add value
-		sm.data.Store(key, &[]V{value})
+		if sm.data.CompareAndSwap(key, old, &ns) {
+			return
+		}
+		// Lost the race with a concurrent update to this key; retry
 	}
 }
 
 // Delete removes a value from the slice associated with the given key
 func (sm *SliceMap[K, V]) Delete(key K, value V) {
-	sm.Lock()
-	defer sm.Unlock()
-
-	if slice_, ok := sm.data.Load(key); ok {
-		slice := slice_.(*[]V)
-		if len(*slice) > 0 {
-			if value == (*slice)[0] {
-				// Remove from the beginning
-				*slice = (*slice)[1:]
-				if len(*slice) == 0 {
-					sm.data.Delete(key)
-				}
-				return
-			} else if value == (*slice)[len(*slice)-1] {
-				// Remove from the end
-				*slice = (*slice)[:len(*slice)-1]
-				if len(*slice) == 0 {
-					sm.data.Delete(key)
-				}
-				return
-			} else if value < (*slice)[0] || value > (*slice)[len(*slice)-1] {
-				return // Value is out of the range of the slice
-			}
+	for {
+		old, ok := sm.data.Load(key)
+		if !ok {
+			return
+		}
+
+		ns, removed := deleteSorted(*old, value)
+		if !removed {
+			return
 		}
 
-		i := sort.Search(len(*slice), func(i int) bool { return (*slice)[i] >= value })
-		if i < len(*slice) && (*slice)[i] == value {
-			// Remove the element at index i
-			*slice = append((*slice)[:i], (*slice)[i+1:]...)
-			if len(*slice) == 0 {
-				sm.data.Delete(key)
+		if len(ns) == 0 {
+			if sm.data.CompareAndDelete(key, old) {
+				return
 			}
+		} else if sm.data.CompareAndSwap(key, old, &ns) {
+			return
 		}
+		// Lost the race with a concurrent update to this key; retry
 	}
 }
 
@@ -103,97 +129,266 @@ func (sm *SliceMap[K, V]) DeleteKey(key K) {
 // Count returns the total number of elements in all slices
 func (sm *SliceMap[K, V]) Count() int64 {
 	var count int64
-	sm.data.Range(func(k, v interface{}) bool {
-		sm.RLock()
-		defer sm.RUnlock()
-
-		slice := v.(*[]V)
+	sm.data.Range(func(_ K, slice *[]V) bool {
 		count += int64(len(*slice))
 		return true
 	})
 	return count
 }
 
-// GetKey returns COPY of the slice associated with the given key
+// GetKey returns the current immutable slice associated with the given key.
+// The returned slice must not be modified; use GetKeyCopy if you need to
+// mutate it.
 func (sm *SliceMap[K, V]) GetKey(key K) *[]V {
-	if slice_, ok := sm.data.Load(key); ok {
-		sm.RLock()
-		defer sm.RUnlock()
+	if slice, ok := sm.data.Load(key); ok {
+		return slice
+	}
+	return nil
+}
 
-		slice := slice_.(*[]V)
+// GetKeyCopy returns a copy of the slice associated with the given key, safe
+// for the caller to mutate
+func (sm *SliceMap[K, V]) GetKeyCopy(key K) *[]V {
+	if slice, ok := sm.data.Load(key); ok {
 		cpy := slices.Clone(*slice)
 		return &cpy
 	}
 	return nil
 }
 
-// IterateValues iterates over all key-value pairs in the map
-func (sm *SliceMap[K, V]) IterateValues(f func(K, V) bool) {
-	sm.data.Range(func(k, slice_ interface{}) bool {
-		sm.RLock()
-		slice := slice_.(*[]V)
-		cpy := slices.Clone(*slice)
-		sm.RUnlock()
+// Keys returns an iterator over all keys in the map
+func (sm *SliceMap[K, V]) Keys() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		sm.data.Range(func(k K, _ *[]V) bool {
+			return yield(k)
+		})
+	}
+}
+
+// Values returns an iterator over the values associated with key, in sorted order
+func (sm *SliceMap[K, V]) Values(key K) iter.Seq[V] {
+	return func(yield func(V) bool) {
+		slice, ok := sm.data.Load(key)
+		if !ok {
+			return
+		}
 
-		for _, v := range cpy {
-			if !f(k.(K), v) {
-				return false
+		for _, v := range *slice {
+			if !yield(v) {
+				return
 			}
 		}
-		return true
-	})
+	}
+}
+
+// All returns an iterator over every key-value pair in the map
+func (sm *SliceMap[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		sm.data.Range(func(k K, slice *[]V) bool {
+			for _, v := range *slice {
+				if !yield(k, v) {
+					return false
+				}
+			}
+			return true
+		})
+	}
+}
+
+// Range returns an iterator over the values associated with key that fall
+// within [lo, hi], in sorted order
+func (sm *SliceMap[K, V]) Range(key K, lo, hi V) iter.Seq[V] {
+	return sm.RangeBounds(key, lo, hi, Inclusive, Inclusive)
+}
+
+// RangeBounds returns an iterator over the values associated with key that
+// satisfy lo/hi according to loBound/hiBound, in sorted order
+func (sm *SliceMap[K, V]) RangeBounds(key K, lo, hi V, loBound, hiBound Bound) iter.Seq[V] {
+	return func(yield func(V) bool) {
+		slice, ok := sm.data.Load(key)
+		if !ok {
+			return
+		}
+
+		i, j := boundsRange(*slice, lo, hi, loBound, hiBound)
+		for _, v := range (*slice)[i:j] {
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// IterateValues iterates over all key-value pairs in the map
+//
+// Deprecated: use All instead.
+func (sm *SliceMap[K, V]) IterateValues(f func(K, V) bool) {
+	for k, v := range sm.All() {
+		if !f(k, v) {
+			return
+		}
+	}
 }
 
 // IterateKeys iterates over all keys in the map
+//
+// Deprecated: use Keys instead.
 func (sm *SliceMap[K, V]) IterateKeys(f func(K) bool) {
-	sm.data.Range(func(k, _ interface{}) bool {
-		if !f(k.(K)) {
-			return false
+	for k := range sm.Keys() {
+		if !f(k) {
+			return
 		}
-		return true
-	})
+	}
 }
 
 // Exist checks if the value v exists for the key k
 func (sm *SliceMap[K, V]) Exist(key K, value V) bool {
-	if slice_, ok := sm.data.Load(key); ok {
-		sm.RLock()
-		defer sm.RUnlock()
+	slice, ok := sm.data.Load(key)
+	if !ok || len(*slice) == 0 {
+		return false
+	}
+	if value < (*slice)[0] || value > (*slice)[len(*slice)-1] {
+		return false // Value is out of the range
+	}
+	i := sort.Search(len(*slice), func(i int) bool { return (*slice)[i] >= value })
+	return i < len(*slice) && (*slice)[i] == value
+}
+
+// Bound selects whether a Range-family endpoint includes or excludes values
+// equal to it.
+type Bound int
+
+const (
+	Inclusive Bound = iota
+	Exclusive
+)
+
+// boundsRange returns the half-open index range [lo, hi) within slice that
+// covers all values v satisfying lo/hi according to loBound/hiBound, using
+// binary search.
+func boundsRange[V constraints.Ordered](slice []V, lo, hi V, loBound, hiBound Bound) (int, int) {
+	var low int
+	if loBound == Exclusive {
+		low = sort.Search(len(slice), func(i int) bool { return slice[i] > lo })
+	} else {
+		low = sort.Search(len(slice), func(i int) bool { return slice[i] >= lo })
+	}
+
+	var high int
+	if hiBound == Exclusive {
+		high = sort.Search(len(slice), func(i int) bool { return slice[i] >= hi })
+	} else {
+		high = sort.Search(len(slice), func(i int) bool { return slice[i] > hi })
+	}
+
+	if high < low {
+		high = low
+	}
+	return low, high
+}
+
+// GetRange returns a copy of the values for key that fall within [lo, hi]
+func (sm *SliceMap[K, V]) GetRange(key K, lo, hi V) []V {
+	return sm.GetRangeBounds(key, lo, hi, Inclusive, Inclusive)
+}
+
+// GetRangeBounds returns a copy of the values for key that satisfy lo/hi
+// according to loBound/hiBound, e.g. GetRangeBounds(key, lo, hi, Inclusive,
+// Exclusive) returns the half-open range [lo, hi)
+func (sm *SliceMap[K, V]) GetRangeBounds(key K, lo, hi V, loBound, hiBound Bound) []V {
+	if slice, ok := sm.data.Load(key); ok {
+		i, j := boundsRange(*slice, lo, hi, loBound, hiBound)
+		return slices.Clone((*slice)[i:j])
+	}
+	return nil
+}
+
+// CountRange returns the number of values for key that fall within [lo, hi]
+func (sm *SliceMap[K, V]) CountRange(key K, lo, hi V) int {
+	return sm.CountRangeBounds(key, lo, hi, Inclusive, Inclusive)
+}
+
+// CountRangeBounds returns the number of values for key that satisfy lo/hi
+// according to loBound/hiBound
+func (sm *SliceMap[K, V]) CountRangeBounds(key K, lo, hi V, loBound, hiBound Bound) int {
+	if slice, ok := sm.data.Load(key); ok {
+		i, j := boundsRange(*slice, lo, hi, loBound, hiBound)
+		return j - i
+	}
+	return 0
+}
+
+// DeleteRange removes all values for key that fall within [lo, hi] and
+// returns the number of values removed. The key is dropped if the slice
+// becomes empty.
+func (sm *SliceMap[K, V]) DeleteRange(key K, lo, hi V) int {
+	return sm.DeleteRangeBounds(key, lo, hi, Inclusive, Inclusive)
+}
+
+// DeleteRangeBounds removes all values for key that satisfy lo/hi according
+// to loBound/hiBound and returns the number of values removed. The key is
+// dropped if the slice becomes empty.
+func (sm *SliceMap[K, V]) DeleteRangeBounds(key K, lo, hi V, loBound, hiBound Bound) int {
+	for {
+		old, ok := sm.data.Load(key)
+		if !ok {
+			return 0
+		}
+
+		i, j := boundsRange(*old, lo, hi, loBound, hiBound)
+		removed := j - i
+		if removed == 0 {
+			return 0
+		}
 
-		slice := slice_.(*[]V)
-		if len(*slice) == 0 {
-			return false
+		ns := make([]V, 0, len(*old)-removed)
+		ns = append(ns, (*old)[:i]...)
+		ns = append(ns, (*old)[j:]...)
+
+		if len(ns) == 0 {
+			if sm.data.CompareAndDelete(key, old) {
+				return removed
+			}
+		} else if sm.data.CompareAndSwap(key, old, &ns) {
+			return removed
 		}
-		if value < (*slice)[0] || value > (*slice)[len(*slice)-1] {
-			return false // Value is out of the range
+		// Lost the race with a concurrent update to this key; retry
+	}
+}
+
+// IterateRange iterates over the values for key that fall within [lo, hi],
+// calling f for each value in sorted order until f returns false
+//
+// Deprecated: use Range instead.
+func (sm *SliceMap[K, V]) IterateRange(key K, lo, hi V, f func(V) bool) {
+	for v := range sm.Range(key, lo, hi) {
+		if !f(v) {
+			return
 		}
-		i := sort.Search(len(*slice), func(i int) bool { return (*slice)[i] >= value })
-		return i < len(*slice) && (*slice)[i] == value
 	}
-	return false
 }
 
 // AddSlice adds multiple values to the slice associated with the given key
 func (sm *SliceMap[K, V]) AddSlice(key K, values []V) {
-	sm.Lock()
-	defer sm.Unlock()
-
 	slices.Sort(values)
 	values = slices.Compact(values)
 
-	slice_, was := sm.data.Load(key)
+	for {
+		old, ok := sm.data.Load(key)
+		if !ok {
+			ns := make([]V, len(values))
+			copy(ns, values)
+			if _, loaded := sm.data.LoadOrStore(key, &ns); !loaded {
+				return
+			}
+			continue // Someone else created the key first; retry against it
+		}
 
-	if !was {
-		// Если ключа нет, просто копируем values
-		ns := make([]V, len(values))
-		copy(ns, values)
-		sm.data.Store(key, &ns)
-	} else {
-		//sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
-		// Если ключ есть, объединяем новые и старые значения, сохраняя уникальность и порядок
-		slice := slice_.(*[]V)
-		*slice = mergeUniqueSorted(*slice, values)
-		sm.data.Store(key, slice)
+		ns := mergeUniqueSorted(*old, values)
+		if sm.data.CompareAndSwap(key, old, &ns) {
+			return
+		}
+		// Lost the race with a concurrent update to this key; retry
 	}
 }
 
@@ -223,3 +418,152 @@ func mergeUniqueSorted[V constraints.Ordered](a, b []V) []V {
 	}
 	return result
 }
+
+// heapNode is one entry of the min-heap used by Union
+type heapNode[V constraints.Ordered] struct {
+	value V
+	slice int
+}
+
+// minHeap is a container/heap.Interface over heapNode, ordered by value
+type minHeap[V constraints.Ordered] []heapNode[V]
+
+func (h minHeap[V]) Len() int            { return len(h) }
+func (h minHeap[V]) Less(i, j int) bool  { return h[i].value < h[j].value }
+func (h minHeap[V]) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *minHeap[V]) Push(x interface{}) { *h = append(*h, x.(heapNode[V])) }
+func (h *minHeap[V]) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	*h = old[:n-1]
+	return item
+}
+
+// Union returns the sorted, deduplicated union of the values across keys, by
+// k-way merging their (already sorted, unique) slices with a min-heap.
+// Missing keys are treated as empty.
+func (sm *SliceMap[K, V]) Union(keys ...K) []V {
+	slices_ := make([][]V, 0, len(keys))
+	for _, key := range keys {
+		if slice, ok := sm.data.Load(key); ok && len(*slice) > 0 {
+			slices_ = append(slices_, *slice)
+		}
+	}
+	if len(slices_) == 0 {
+		return nil
+	}
+
+	pos := make([]int, len(slices_))
+	h := make(minHeap[V], 0, len(slices_))
+	for i, s := range slices_ {
+		h = append(h, heapNode[V]{value: s[0], slice: i})
+		pos[i] = 1
+	}
+	heap.Init(&h)
+
+	var result []V
+	for h.Len() > 0 {
+		top := heap.Pop(&h).(heapNode[V])
+		if len(result) == 0 || result[len(result)-1] != top.value {
+			result = append(result, top.value)
+		}
+		if i := top.slice; pos[i] < len(slices_[i]) {
+			heap.Push(&h, heapNode[V]{value: slices_[i][pos[i]], slice: i})
+			pos[i]++
+		}
+	}
+	return result
+}
+
+// Intersect returns the sorted values common to every key's slice, by
+// taking the largest head across all k slices as the candidate and
+// advancing every slice up to it until they agree. A missing key makes the
+// intersection empty.
+func (sm *SliceMap[K, V]) Intersect(keys ...K) []V {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	slices_ := make([][]V, len(keys))
+	for i, key := range keys {
+		if slice, ok := sm.data.Load(key); ok {
+			slices_[i] = *slice
+		}
+		if len(slices_[i]) == 0 {
+			return nil
+		}
+	}
+
+	pos := make([]int, len(slices_))
+	var result []V
+	for {
+		if pos[0] >= len(slices_[0]) {
+			return result
+		}
+		candidate := slices_[0][pos[0]]
+		for i := 1; i < len(slices_); i++ {
+			if pos[i] >= len(slices_[i]) {
+				return result
+			}
+			if v := slices_[i][pos[i]]; v > candidate {
+				candidate = v
+			}
+		}
+
+		matchAll := true
+		for i, s := range slices_ {
+			for pos[i] < len(s) && s[pos[i]] < candidate {
+				pos[i]++
+			}
+			if pos[i] >= len(s) {
+				return result
+			}
+			if s[pos[i]] != candidate {
+				matchAll = false
+			}
+		}
+
+		if matchAll {
+			result = append(result, candidate)
+			for i := range slices_ {
+				pos[i]++
+			}
+		}
+	}
+}
+
+// Difference returns the sorted values in a's slice that do not appear in
+// any of b's slices, via a parallel two-pointer scan against each of b
+func (sm *SliceMap[K, V]) Difference(a K, b ...K) []V {
+	aSlice_, ok := sm.data.Load(a)
+	if !ok || len(*aSlice_) == 0 {
+		return nil
+	}
+	aSlice := *aSlice_
+
+	bSlices := make([][]V, 0, len(b))
+	for _, key := range b {
+		if slice, ok := sm.data.Load(key); ok && len(*slice) > 0 {
+			bSlices = append(bSlices, *slice)
+		}
+	}
+
+	bPos := make([]int, len(bSlices))
+	var result []V
+	for _, v := range aSlice {
+		skip := false
+		for i, s := range bSlices {
+			for bPos[i] < len(s) && s[bPos[i]] < v {
+				bPos[i]++
+			}
+			if bPos[i] < len(s) && s[bPos[i]] == v {
+				skip = true
+			}
+		}
+		if !skip {
+			result = append(result, v)
+		}
+	}
+	return result
+}
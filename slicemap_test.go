@@ -1,7 +1,12 @@
 package slicemap
 
 import (
+	"math/rand"
+	"slices"
 	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"testing"
 )
 
@@ -87,6 +92,96 @@ func BenchmarkDelete(b *testing.B) {
 	}
 }
 
+// BenchmarkAddSequentialN shows the O(n) copy-on-insert cost of Add as the
+// per-key set grows, in contrast to TreeMap's O(log n) Add
+// (BenchmarkTreeMapAddSequentialN in the treemap package, over the same n
+// and insert order so the two are directly comparable). Values are
+// inserted in random order rather than ascending; every Add copies the
+// key's whole slice into a new one, whatever the insert position.
+func BenchmarkAddSequentialN(b *testing.B) {
+	for _, n := range []int{10, 100, 1_000, 10_000, 100_000} {
+		values := rand.New(rand.NewSource(1)).Perm(n)
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				sm := NewSliceMap[int, int]()
+				for _, v := range values {
+					sm.Add(1, v)
+				}
+			}
+		})
+	}
+}
+
+// legacySliceMap is a stand-in for the pre-typedsync.Map SliceMap: a global
+// RWMutex guarding a plain sync.Map of interface{}, requiring a type
+// assertion on every access. It exists only so BenchmarkAddLegacy and
+// BenchmarkAddDeleteLegacy can be compared against BenchmarkAdd and
+// BenchmarkAddDelete to quantify the win from typedsync.Map.
+type legacySliceMap struct {
+	sync.RWMutex
+	data sync.Map
+}
+
+func (sm *legacySliceMap) add(key, value int) {
+	sm.Lock()
+	defer sm.Unlock()
+
+	slice_, ok := sm.data.Load(key)
+	if !ok {
+		sm.data.Store(key, &[]int{value})
+		return
+	}
+
+	slice := slice_.(*[]int)
+	i := sort.Search(len(*slice), func(i int) bool { return (*slice)[i] >= value })
+	if i < len(*slice) && (*slice)[i] == value {
+		return // Value already exists
+	}
+	*slice = append(*slice, value)
+	copy((*slice)[i+1:], (*slice)[i:])
+	(*slice)[i] = value
+}
+
+func (sm *legacySliceMap) delete(key, value int) {
+	sm.Lock()
+	defer sm.Unlock()
+
+	slice_, ok := sm.data.Load(key)
+	if !ok {
+		return
+	}
+
+	slice := slice_.(*[]int)
+	i := sort.Search(len(*slice), func(i int) bool { return (*slice)[i] >= value })
+	if i >= len(*slice) || (*slice)[i] != value {
+		return
+	}
+	*slice = append((*slice)[:i], (*slice)[i+1:]...)
+	if len(*slice) == 0 {
+		sm.data.Delete(key)
+	}
+}
+
+// BenchmarkAddLegacy runs the BenchmarkAdd workload against legacySliceMap,
+// for comparison with BenchmarkAdd's typedsync.Map-backed implementation.
+func BenchmarkAddLegacy(b *testing.B) {
+	sm := &legacySliceMap{}
+	for i := 0; i < b.N; i++ {
+		sm.add(1, i)
+	}
+}
+
+// BenchmarkAddDeleteLegacy runs the BenchmarkAddDelete workload against
+// legacySliceMap, for comparison with BenchmarkAddDelete's
+// typedsync.Map-backed implementation.
+func BenchmarkAddDeleteLegacy(b *testing.B) {
+	sm := &legacySliceMap{}
+	for i := 0; i < b.N; i++ {
+		sm.add(1, i)
+		sm.delete(1, i)
+	}
+}
+
 func TestAddSlice(t *testing.T) {
 	sm := NewSliceMap[int, int]()
 
@@ -120,3 +215,345 @@ func TestAddSlice(t *testing.T) {
 		t.Errorf("Slice for key 2 should be sorted")
 	}
 }
+
+func TestRangeQueries(t *testing.T) {
+	sm := NewSliceMap[int, int]()
+	sm.AddSlice(1, []int{10, 20, 30, 40, 50})
+
+	// Range entirely inside the slice
+	if got := sm.GetRange(1, 20, 40); !sort.IntsAreSorted(got) || len(got) != 3 {
+		t.Errorf("GetRange(20, 40) = %v, want [20 30 40]", got)
+	}
+	if n := sm.CountRange(1, 20, 40); n != 3 {
+		t.Errorf("CountRange(20, 40) = %d, want 3", n)
+	}
+
+	// Range covering the entire slice
+	if got := sm.GetRange(1, 0, 100); len(got) != 5 {
+		t.Errorf("GetRange(0, 100) = %v, want all 5 elements", got)
+	}
+
+	// Range outside the slice
+	if got := sm.GetRange(1, 1000, 2000); len(got) != 0 {
+		t.Errorf("GetRange(1000, 2000) = %v, want empty", got)
+	}
+	if n := sm.CountRange(1, -100, -1); n != 0 {
+		t.Errorf("CountRange(-100, -1) = %d, want 0", n)
+	}
+
+	// Range on a missing key
+	if got := sm.GetRange(999, 0, 100); got != nil {
+		t.Errorf("GetRange on missing key = %v, want nil", got)
+	}
+
+	// Empty range (lo > hi)
+	if got := sm.GetRange(1, 40, 20); len(got) != 0 {
+		t.Errorf("GetRange(40, 20) = %v, want empty", got)
+	}
+
+	var visited []int
+	sm.IterateRange(1, 20, 40, func(v int) bool {
+		visited = append(visited, v)
+		return true
+	})
+	if len(visited) != 3 || visited[0] != 20 || visited[2] != 40 {
+		t.Errorf("IterateRange visited = %v, want [20 30 40]", visited)
+	}
+
+	// Stop iteration early
+	visited = nil
+	sm.IterateRange(1, 10, 50, func(v int) bool {
+		visited = append(visited, v)
+		return v != 30
+	})
+	if len(visited) != 3 {
+		t.Errorf("IterateRange early stop visited = %v, want 3 elements", visited)
+	}
+
+	// Delete a range covering the entire slice drops the key
+	n := sm.DeleteRange(1, 0, 100)
+	if n != 5 {
+		t.Errorf("DeleteRange(0, 100) = %d, want 5", n)
+	}
+	if sm.GetKey(1) != nil {
+		t.Errorf("Expected nil for key 1 after DeleteRange removed everything")
+	}
+
+	// DeleteRange on a partial range
+	sm.AddSlice(2, []int{1, 2, 3, 4, 5})
+	if n := sm.DeleteRange(2, 2, 4); n != 3 {
+		t.Errorf("DeleteRange(2, 4) = %d, want 3", n)
+	}
+	if got := *sm.GetKey(2); len(got) != 2 || got[0] != 1 || got[1] != 5 {
+		t.Errorf("GetKey(2) after DeleteRange = %v, want [1 5]", got)
+	}
+
+	// DeleteRange with no matches
+	if n := sm.DeleteRange(2, 100, 200); n != 0 {
+		t.Errorf("DeleteRange(100, 200) = %d, want 0", n)
+	}
+}
+
+func TestRangeQueriesBounds(t *testing.T) {
+	sm := NewSliceMap[int, int]()
+	sm.AddSlice(1, []int{10, 20, 30, 40, 50})
+
+	// Half-open [lo, hi) excludes the upper bound
+	if got := sm.GetRangeBounds(1, 20, 40, Inclusive, Exclusive); !slices.Equal(got, []int{20, 30}) {
+		t.Errorf("GetRangeBounds(20, 40, Inclusive, Exclusive) = %v, want [20 30]", got)
+	}
+	if n := sm.CountRangeBounds(1, 20, 40, Inclusive, Exclusive); n != 2 {
+		t.Errorf("CountRangeBounds(20, 40, Inclusive, Exclusive) = %d, want 2", n)
+	}
+
+	// Half-open (lo, hi] excludes the lower bound
+	if got := sm.GetRangeBounds(1, 20, 40, Exclusive, Inclusive); !slices.Equal(got, []int{30, 40}) {
+		t.Errorf("GetRangeBounds(20, 40, Exclusive, Inclusive) = %v, want [30 40]", got)
+	}
+
+	// Fully open (lo, hi) excludes both bounds
+	if got := sm.GetRangeBounds(1, 20, 40, Exclusive, Exclusive); !slices.Equal(got, []int{30}) {
+		t.Errorf("GetRangeBounds(20, 40, Exclusive, Exclusive) = %v, want [30]", got)
+	}
+
+	// Excluding a bound that isn't present in the slice changes nothing
+	if got := sm.GetRangeBounds(1, 15, 45, Exclusive, Exclusive); !slices.Equal(got, []int{20, 30, 40}) {
+		t.Errorf("GetRangeBounds(15, 45, Exclusive, Exclusive) = %v, want [20 30 40]", got)
+	}
+
+	// DeleteRangeBounds respects the half-open upper bound
+	sm.AddSlice(2, []int{1, 2, 3, 4, 5})
+	if n := sm.DeleteRangeBounds(2, 2, 4, Inclusive, Exclusive); n != 2 {
+		t.Errorf("DeleteRangeBounds(2, 4, Inclusive, Exclusive) = %d, want 2", n)
+	}
+	if got := *sm.GetKey(2); !slices.Equal(got, []int{1, 4, 5}) {
+		t.Errorf("GetKey(2) after DeleteRangeBounds = %v, want [1 4 5]", got)
+	}
+}
+
+func TestIterators(t *testing.T) {
+	sm := NewSliceMap[int, int]()
+	sm.AddSlice(1, []int{10, 20, 30})
+	sm.AddSlice(2, []int{5})
+
+	var keys []int
+	for k := range sm.Keys() {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	if len(keys) != 2 || keys[0] != 1 || keys[1] != 2 {
+		t.Errorf("Keys() = %v, want [1 2]", keys)
+	}
+
+	var values []int
+	for v := range sm.Values(1) {
+		values = append(values, v)
+	}
+	if len(values) != 3 || values[0] != 10 || values[2] != 30 {
+		t.Errorf("Values(1) = %v, want [10 20 30]", values)
+	}
+
+	// Missing key yields nothing
+	for range sm.Values(999) {
+		t.Errorf("Values on missing key should not yield")
+	}
+
+	total := 0
+	for _, v := range sm.All() {
+		total += v
+	}
+	if total != 10+20+30+5 {
+		t.Errorf("All() total = %d, want %d", total, 10+20+30+5)
+	}
+
+	// Break out of All() mid-iteration
+	count := 0
+	for range sm.All() {
+		count++
+		break
+	}
+	if count != 1 {
+		t.Errorf("All() should stop after break, got count %d", count)
+	}
+
+	var ranged []int
+	for v := range sm.Range(1, 15, 30) {
+		ranged = append(ranged, v)
+	}
+	if len(ranged) != 2 || ranged[0] != 20 || ranged[1] != 30 {
+		t.Errorf("Range(15, 30) = %v, want [20 30]", ranged)
+	}
+
+	var rangedHalfOpen []int
+	for v := range sm.RangeBounds(1, 10, 30, Inclusive, Exclusive) {
+		rangedHalfOpen = append(rangedHalfOpen, v)
+	}
+	if len(rangedHalfOpen) != 2 || rangedHalfOpen[0] != 10 || rangedHalfOpen[1] != 20 {
+		t.Errorf("RangeBounds(10, 30, Inclusive, Exclusive) = %v, want [10 20]", rangedHalfOpen)
+	}
+
+	// The deprecated callback wrappers still work
+	var viaCallback []int
+	sm.IterateKeys(func(k int) bool {
+		viaCallback = append(viaCallback, k)
+		return true
+	})
+	if len(viaCallback) != 2 {
+		t.Errorf("IterateKeys visited %d keys, want 2", len(viaCallback))
+	}
+
+	viaCallback = nil
+	sm.IterateRange(1, 15, 30, func(v int) bool {
+		viaCallback = append(viaCallback, v)
+		return true
+	})
+	if len(viaCallback) != 2 || viaCallback[0] != 20 {
+		t.Errorf("IterateRange(15, 30) = %v, want [20 30]", viaCallback)
+	}
+}
+
+func TestGetKeyCopy(t *testing.T) {
+	sm := NewSliceMap[int, int]()
+	sm.Add(1, 10)
+	sm.Add(1, 20)
+
+	cpy := sm.GetKeyCopy(1)
+	(*cpy)[0] = 999
+
+	if got := *sm.GetKey(1); got[0] != 10 {
+		t.Errorf("mutating a GetKeyCopy result affected the map: got %v", got)
+	}
+
+	if sm.GetKeyCopy(999) != nil {
+		t.Errorf("GetKeyCopy on missing key should return nil")
+	}
+}
+
+// TestConcurrentStress hammers a SliceMap from many goroutines, both on
+// distinct keys (which should never contend under the lock-free design) and
+// on a small set of overlapping keys (which must still serialize correctly
+// via CompareAndSwap/CompareAndDelete retries).
+func TestConcurrentStress(t *testing.T) {
+	const perGoroutine = 500
+
+	t.Run("disjoint keys", func(t *testing.T) {
+		sm := NewSliceMap[int, int]()
+		var wg sync.WaitGroup
+		for g := 0; g < 8; g++ {
+			wg.Add(1)
+			go func(key int) {
+				defer wg.Done()
+				for i := 0; i < perGoroutine; i++ {
+					sm.Add(key, i)
+				}
+				for i := 0; i < perGoroutine/2; i++ {
+					sm.Delete(key, i)
+				}
+			}(g)
+		}
+		wg.Wait()
+
+		for g := 0; g < 8; g++ {
+			got := sm.GetKey(g)
+			if got == nil || len(*got) != perGoroutine/2 {
+				t.Errorf("key %d: got %v elements, want %d", g, got, perGoroutine/2)
+			}
+		}
+	})
+
+	t.Run("overlapping keys", func(t *testing.T) {
+		sm := NewSliceMap[int, int]()
+		const keys = 4
+		var wg sync.WaitGroup
+		for g := 0; g < 16; g++ {
+			wg.Add(1)
+			go func(id int) {
+				defer wg.Done()
+				for i := 0; i < perGoroutine; i++ {
+					sm.Add(id%keys, i)
+				}
+			}(g)
+		}
+		wg.Wait()
+
+		for k := 0; k < keys; k++ {
+			got := sm.GetKey(k)
+			if got == nil || len(*got) != perGoroutine {
+				t.Errorf("key %d: got %d elements, want %d", k, len(*got), perGoroutine)
+			}
+			if !sort.IntsAreSorted(*got) {
+				t.Errorf("key %d: not sorted: %v", k, *got)
+			}
+		}
+	})
+}
+
+// BenchmarkAddDisjointKeysParallel measures Add throughput when concurrent
+// goroutines each own a distinct key. With the lock-free per-key design this
+// should scale close to linearly with GOMAXPROCS, unlike a single global
+// lock which would serialize all of them.
+func BenchmarkAddDisjointKeysParallel(b *testing.B) {
+	sm := NewSliceMap[int, int]()
+	var nextKey int64
+	b.RunParallel(func(pb *testing.PB) {
+		key := int(atomic.AddInt64(&nextKey, 1))
+		i := 0
+		for pb.Next() {
+			sm.Add(key, i)
+			i++
+		}
+	})
+}
+
+func TestSetOperations(t *testing.T) {
+	sm := NewSliceMap[int, int]()
+	sm.AddSlice(1, []int{1, 2, 3, 4, 5})
+	sm.AddSlice(2, []int{3, 4, 5, 6, 7})
+	sm.AddSlice(3, []int{4, 5, 6, 7, 8})
+
+	if got := sm.Union(1, 2, 3); !sort.IntsAreSorted(got) || !slices.Equal(got, []int{1, 2, 3, 4, 5, 6, 7, 8}) {
+		t.Errorf("Union(1, 2, 3) = %v, want [1 2 3 4 5 6 7 8]", got)
+	}
+	if got := sm.Intersect(1, 2, 3); !slices.Equal(got, []int{4, 5}) {
+		t.Errorf("Intersect(1, 2, 3) = %v, want [4 5]", got)
+	}
+	if got := sm.Difference(1, 2, 3); !slices.Equal(got, []int{1, 2}) {
+		t.Errorf("Difference(1, 2, 3) = %v, want [1 2]", got)
+	}
+
+	// Single-key cases
+	if got := sm.Union(1); !slices.Equal(got, []int{1, 2, 3, 4, 5}) {
+		t.Errorf("Union(1) = %v, want [1 2 3 4 5]", got)
+	}
+	if got := sm.Intersect(1); !slices.Equal(got, []int{1, 2, 3, 4, 5}) {
+		t.Errorf("Intersect(1) = %v, want [1 2 3 4 5]", got)
+	}
+	if got := sm.Difference(1); !slices.Equal(got, []int{1, 2, 3, 4, 5}) {
+		t.Errorf("Difference(1) = %v, want [1 2 3 4 5]", got)
+	}
+
+	// Missing keys
+	if got := sm.Union(1, 999); !slices.Equal(got, []int{1, 2, 3, 4, 5}) {
+		t.Errorf("Union(1, 999) = %v, want [1 2 3 4 5]", got)
+	}
+	if got := sm.Intersect(1, 999); got != nil {
+		t.Errorf("Intersect(1, 999) = %v, want nil", got)
+	}
+	if got := sm.Difference(1, 999); !slices.Equal(got, []int{1, 2, 3, 4, 5}) {
+		t.Errorf("Difference(1, 999) = %v, want [1 2 3 4 5]", got)
+	}
+	if got := sm.Difference(999, 1); got != nil {
+		t.Errorf("Difference(999, 1) = %v, want nil", got)
+	}
+
+	// Empty inputs
+	if got := sm.Union(); got != nil {
+		t.Errorf("Union() = %v, want nil", got)
+	}
+	if got := sm.Intersect(); got != nil {
+		t.Errorf("Intersect() = %v, want nil", got)
+	}
+	if got := sm.Difference(1); !slices.Equal(got, []int{1, 2, 3, 4, 5}) {
+		t.Errorf("Difference(1) with no b keys = %v, want [1 2 3 4 5]", got)
+	}
+}
@@ -0,0 +1,328 @@
+package treemap
+
+import "golang.org/x/exp/constraints"
+
+// rbColor is the color of a red-black tree node
+type rbColor uint8
+
+const (
+	black rbColor = iota
+	red
+)
+
+// rbNode is a single node of a red-black tree
+type rbNode[V constraints.Ordered] struct {
+	value               V
+	color               rbColor
+	left, right, parent *rbNode[V]
+}
+
+// rbTree is a red-black tree holding unique, ordered values. All operations
+// (insert, delete, search) run in O(log n)
+type rbTree[V constraints.Ordered] struct {
+	root *rbNode[V]
+	size int
+}
+
+func nodeColor[V constraints.Ordered](n *rbNode[V]) rbColor {
+	if n == nil {
+		return black
+	}
+	return n.color
+}
+
+func (t *rbTree[V]) rotateLeft(x *rbNode[V]) {
+	y := x.right
+	x.right = y.left
+	if y.left != nil {
+		y.left.parent = x
+	}
+	y.parent = x.parent
+	if x.parent == nil {
+		t.root = y
+	} else if x == x.parent.left {
+		x.parent.left = y
+	} else {
+		x.parent.right = y
+	}
+	y.left = x
+	x.parent = y
+}
+
+func (t *rbTree[V]) rotateRight(x *rbNode[V]) {
+	y := x.left
+	x.left = y.right
+	if y.right != nil {
+		y.right.parent = x
+	}
+	y.parent = x.parent
+	if x.parent == nil {
+		t.root = y
+	} else if x == x.parent.right {
+		x.parent.right = y
+	} else {
+		x.parent.left = y
+	}
+	y.right = x
+	x.parent = y
+}
+
+// search returns the node holding value, or nil if it is not present
+func (t *rbTree[V]) search(value V) *rbNode[V] {
+	n := t.root
+	for n != nil {
+		switch {
+		case value < n.value:
+			n = n.left
+		case value > n.value:
+			n = n.right
+		default:
+			return n
+		}
+	}
+	return nil
+}
+
+// insert adds value to the tree, returning false if it was already present
+func (t *rbTree[V]) insert(value V) bool {
+	var parent *rbNode[V]
+	cur := t.root
+	for cur != nil {
+		parent = cur
+		switch {
+		case value < cur.value:
+			cur = cur.left
+		case value > cur.value:
+			cur = cur.right
+		default:
+			return false
+		}
+	}
+
+	n := &rbNode[V]{value: value, color: red, parent: parent}
+	switch {
+	case parent == nil:
+		t.root = n
+	case value < parent.value:
+		parent.left = n
+	default:
+		parent.right = n
+	}
+	t.insertFixup(n)
+	t.size++
+	return true
+}
+
+func (t *rbTree[V]) insertFixup(z *rbNode[V]) {
+	for z.parent != nil && z.parent.color == red {
+		gp := z.parent.parent
+		if gp == nil {
+			break
+		}
+		if z.parent == gp.left {
+			uncle := gp.right
+			if nodeColor(uncle) == red {
+				z.parent.color = black
+				uncle.color = black
+				gp.color = red
+				z = gp
+				continue
+			}
+			if z == z.parent.right {
+				z = z.parent
+				t.rotateLeft(z)
+			}
+			z.parent.color = black
+			gp.color = red
+			t.rotateRight(gp)
+		} else {
+			uncle := gp.left
+			if nodeColor(uncle) == red {
+				z.parent.color = black
+				uncle.color = black
+				gp.color = red
+				z = gp
+				continue
+			}
+			if z == z.parent.left {
+				z = z.parent
+				t.rotateRight(z)
+			}
+			z.parent.color = black
+			gp.color = red
+			t.rotateLeft(gp)
+		}
+	}
+	t.root.color = black
+}
+
+// delete removes value from the tree, returning false if it was not present
+func (t *rbTree[V]) delete(value V) bool {
+	z := t.search(value)
+	if z == nil {
+		return false
+	}
+	t.deleteNode(z)
+	t.size--
+	return true
+}
+
+func (t *rbTree[V]) transplant(u, v *rbNode[V]) {
+	switch {
+	case u.parent == nil:
+		t.root = v
+	case u == u.parent.left:
+		u.parent.left = v
+	default:
+		u.parent.right = v
+	}
+	if v != nil {
+		v.parent = u.parent
+	}
+}
+
+func minimum[V constraints.Ordered](n *rbNode[V]) *rbNode[V] {
+	for n.left != nil {
+		n = n.left
+	}
+	return n
+}
+
+func (t *rbTree[V]) deleteNode(z *rbNode[V]) {
+	y := z
+	yOriginalColor := y.color
+	var x, xParent *rbNode[V]
+
+	switch {
+	case z.left == nil:
+		x, xParent = z.right, z.parent
+		t.transplant(z, z.right)
+	case z.right == nil:
+		x, xParent = z.left, z.parent
+		t.transplant(z, z.left)
+	default:
+		y = minimum(z.right)
+		yOriginalColor = y.color
+		x = y.right
+		if y.parent == z {
+			xParent = y
+		} else {
+			xParent = y.parent
+			t.transplant(y, y.right)
+			y.right = z.right
+			y.right.parent = y
+		}
+		t.transplant(z, y)
+		y.left = z.left
+		y.left.parent = y
+		y.color = z.color
+	}
+
+	if yOriginalColor == black {
+		t.deleteFixup(x, xParent)
+	}
+}
+
+// deleteFixup restores the red-black invariants after deleteNode has removed
+// a black node, leaving x (possibly nil) as a "doubly black" node under
+// parent. x has no parent pointer of its own when nil, so parent is tracked
+// alongside it.
+func (t *rbTree[V]) deleteFixup(x, parent *rbNode[V]) {
+	for x != t.root && nodeColor(x) == black && parent != nil {
+		if x == parent.left {
+			w := parent.right
+			if nodeColor(w) == red {
+				w.color = black
+				parent.color = red
+				t.rotateLeft(parent)
+				w = parent.right
+			}
+			if nodeColor(w.left) == black && nodeColor(w.right) == black {
+				w.color = red
+				x, parent = parent, parent.parent
+				continue
+			}
+			if nodeColor(w.right) == black {
+				w.left.color = black
+				w.color = red
+				t.rotateRight(w)
+				w = parent.right
+			}
+			w.color = parent.color
+			parent.color = black
+			w.right.color = black
+			t.rotateLeft(parent)
+			x, parent = t.root, nil
+		} else {
+			w := parent.left
+			if nodeColor(w) == red {
+				w.color = black
+				parent.color = red
+				t.rotateRight(parent)
+				w = parent.left
+			}
+			if nodeColor(w.right) == black && nodeColor(w.left) == black {
+				w.color = red
+				x, parent = parent, parent.parent
+				continue
+			}
+			if nodeColor(w.left) == black {
+				w.right.color = black
+				w.color = red
+				t.rotateLeft(w)
+				w = parent.left
+			}
+			w.color = parent.color
+			parent.color = black
+			w.left.color = black
+			t.rotateRight(parent)
+			x, parent = t.root, nil
+		}
+	}
+	if x != nil {
+		x.color = black
+	}
+}
+
+// inorder returns every value in the tree in sorted order
+func (t *rbTree[V]) inorder() []V {
+	result := make([]V, 0, t.size)
+	var walk func(n *rbNode[V])
+	walk = func(n *rbNode[V]) {
+		if n == nil {
+			return
+		}
+		walk(n.left)
+		result = append(result, n.value)
+		walk(n.right)
+	}
+	walk(t.root)
+	return result
+}
+
+// inorderRange visits, in sorted order, every value v that satisfies lo/hi
+// according to loBound/hiBound, calling f for each until f returns false.
+// Subtrees fully outside [lo, hi] are pruned, so the walk costs O(log n + k)
+// for k results
+func (t *rbTree[V]) inorderRange(lo, hi V, loBound, hiBound Bound, f func(V) bool) {
+	var walk func(n *rbNode[V]) bool
+	walk = func(n *rbNode[V]) bool {
+		if n == nil {
+			return true
+		}
+		if n.value > hi || (hiBound == Exclusive && n.value == hi) {
+			return walk(n.left)
+		}
+		if n.value < lo || (loBound == Exclusive && n.value == lo) {
+			return walk(n.right)
+		}
+		if !walk(n.left) {
+			return false
+		}
+		if !f(n.value) {
+			return false
+		}
+		return walk(n.right)
+	}
+	walk(t.root)
+}
@@ -0,0 +1,214 @@
+// Package treemap provides TreeMap, a map of per-key red-black trees.
+//
+// It mirrors the core of the SliceMap API (see the parent slicemap
+// package): Add, Delete, DeleteKey, Exist, GetKey, Count and the range
+// queries. It has no AddSlice, GetKeyCopy, iterator or set-algebra methods.
+// It trades SliceMap's O(n) copy-on-insert sorted slice for a red-black tree with
+// O(log n) Add/Delete. SliceMap is cache-friendlier and faster for small
+// per-key sets; TreeMap scales better once a key's set grows large.
+package treemap
+
+import (
+	"sync"
+
+	"golang.org/x/exp/constraints"
+)
+
+// TreeMap is a map of red-black trees of ordered values
+type TreeMap[K, V constraints.Ordered] struct {
+	sync.RWMutex
+	data sync.Map
+}
+
+// NewTreeMap creates a new TreeMap
+func NewTreeMap[K, V constraints.Ordered]() *TreeMap[K, V] {
+	return &TreeMap[K, V]{}
+}
+
+// Add adds a value to the tree associated with the given key
+func (tm *TreeMap[K, V]) Add(key K, value V) {
+	tm.Lock()
+	defer tm.Unlock()
+
+	if tree_, ok := tm.data.Load(key); ok {
+		tree_.(*rbTree[V]).insert(value)
+		return
+	}
+	tree := &rbTree[V]{}
+	tree.insert(value)
+	tm.data.Store(key, tree)
+}
+
+// Delete removes a value from the tree associated with the given key
+func (tm *TreeMap[K, V]) Delete(key K, value V) {
+	tm.Lock()
+	defer tm.Unlock()
+
+	tree_, ok := tm.data.Load(key)
+	if !ok {
+		return
+	}
+	tree := tree_.(*rbTree[V])
+	tree.delete(value)
+	if tree.size == 0 {
+		tm.data.Delete(key)
+	}
+}
+
+// DeleteKey removes the key and its associated tree from the map
+func (tm *TreeMap[K, V]) DeleteKey(key K) {
+	tm.data.Delete(key)
+}
+
+// Count returns the total number of elements in all trees
+func (tm *TreeMap[K, V]) Count() int64 {
+	var count int64
+	tm.data.Range(func(_, v interface{}) bool {
+		tm.RLock()
+		defer tm.RUnlock()
+
+		count += int64(v.(*rbTree[V]).size)
+		return true
+	})
+	return count
+}
+
+// GetKey returns a sorted snapshot of the values associated with the given key
+func (tm *TreeMap[K, V]) GetKey(key K) *[]V {
+	if tree_, ok := tm.data.Load(key); ok {
+		tm.RLock()
+		defer tm.RUnlock()
+
+		vals := tree_.(*rbTree[V]).inorder()
+		return &vals
+	}
+	return nil
+}
+
+// Exist checks if the value v exists for the key k
+func (tm *TreeMap[K, V]) Exist(key K, value V) bool {
+	if tree_, ok := tm.data.Load(key); ok {
+		tm.RLock()
+		defer tm.RUnlock()
+
+		return tree_.(*rbTree[V]).search(value) != nil
+	}
+	return false
+}
+
+// Bound selects whether a Range-family endpoint includes or excludes values
+// equal to it.
+type Bound int
+
+const (
+	Inclusive Bound = iota
+	Exclusive
+)
+
+// GetRange returns a sorted snapshot of the values for key that fall within [lo, hi]
+func (tm *TreeMap[K, V]) GetRange(key K, lo, hi V) []V {
+	return tm.GetRangeBounds(key, lo, hi, Inclusive, Inclusive)
+}
+
+// GetRangeBounds returns a sorted snapshot of the values for key that
+// satisfy lo/hi according to loBound/hiBound
+func (tm *TreeMap[K, V]) GetRangeBounds(key K, lo, hi V, loBound, hiBound Bound) []V {
+	if tree_, ok := tm.data.Load(key); ok {
+		tm.RLock()
+		defer tm.RUnlock()
+
+		var result []V
+		tree_.(*rbTree[V]).inorderRange(lo, hi, loBound, hiBound, func(v V) bool {
+			result = append(result, v)
+			return true
+		})
+		return result
+	}
+	return nil
+}
+
+// CountRange returns the number of values for key that fall within [lo, hi]
+func (tm *TreeMap[K, V]) CountRange(key K, lo, hi V) int {
+	return tm.CountRangeBounds(key, lo, hi, Inclusive, Inclusive)
+}
+
+// CountRangeBounds returns the number of values for key that satisfy lo/hi
+// according to loBound/hiBound
+func (tm *TreeMap[K, V]) CountRangeBounds(key K, lo, hi V, loBound, hiBound Bound) int {
+	if tree_, ok := tm.data.Load(key); ok {
+		tm.RLock()
+		defer tm.RUnlock()
+
+		count := 0
+		tree_.(*rbTree[V]).inorderRange(lo, hi, loBound, hiBound, func(V) bool {
+			count++
+			return true
+		})
+		return count
+	}
+	return 0
+}
+
+// DeleteRange removes all values for key that fall within [lo, hi] and
+// returns the number of values removed. The key is dropped if the tree
+// becomes empty.
+func (tm *TreeMap[K, V]) DeleteRange(key K, lo, hi V) int {
+	return tm.DeleteRangeBounds(key, lo, hi, Inclusive, Inclusive)
+}
+
+// DeleteRangeBounds removes all values for key that satisfy lo/hi according
+// to loBound/hiBound and returns the number of values removed. The key is
+// dropped if the tree becomes empty.
+func (tm *TreeMap[K, V]) DeleteRangeBounds(key K, lo, hi V, loBound, hiBound Bound) int {
+	tm.Lock()
+	defer tm.Unlock()
+
+	tree_, ok := tm.data.Load(key)
+	if !ok {
+		return 0
+	}
+
+	tree := tree_.(*rbTree[V])
+	var toDelete []V
+	tree.inorderRange(lo, hi, loBound, hiBound, func(v V) bool {
+		toDelete = append(toDelete, v)
+		return true
+	})
+	for _, v := range toDelete {
+		tree.delete(v)
+	}
+	if tree.size == 0 {
+		tm.data.Delete(key)
+	}
+	return len(toDelete)
+}
+
+// IterateRange iterates over the values for key that fall within [lo, hi],
+// calling f for each value in sorted order until f returns false
+func (tm *TreeMap[K, V]) IterateRange(key K, lo, hi V, f func(V) bool) {
+	tm.IterateRangeBounds(key, lo, hi, Inclusive, Inclusive, f)
+}
+
+// IterateRangeBounds iterates over the values for key that satisfy lo/hi
+// according to loBound/hiBound, calling f for each value in sorted order
+// until f returns false
+func (tm *TreeMap[K, V]) IterateRangeBounds(key K, lo, hi V, loBound, hiBound Bound, f func(V) bool) {
+	tree_, ok := tm.data.Load(key)
+	if !ok {
+		return
+	}
+
+	tm.RLock()
+	var cpy []V
+	tree_.(*rbTree[V]).inorderRange(lo, hi, loBound, hiBound, func(v V) bool {
+		cpy = append(cpy, v)
+		return true
+	})
+	tm.RUnlock()
+
+	for _, v := range cpy {
+		if !f(v) {
+			return
+		}
+	}
+}
@@ -0,0 +1,265 @@
+package treemap
+
+import (
+	"math/rand"
+	"slices"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+func TestTreeMapOperations(t *testing.T) {
+	tm := NewTreeMap[int, int]()
+	// Test adding elements
+	tm.Add(1, 10)
+	tm.Add(1, 20)
+	tm.Add(2, 10)
+	if len(*tm.GetKey(1)) != 2 {
+		t.Errorf("Expected 2 elements for key 1, got %d", len(*tm.GetKey(1)))
+	}
+
+	if !tm.Exist(1, 20) {
+		t.Errorf("Value not exist but should")
+	}
+
+	// Test deleting an element
+	tm.Delete(1, 10)
+	if len(*tm.GetKey(1)) != 1 {
+		t.Errorf("Expected 1 element for key 1 after deletion, got %d", len(*tm.GetKey(1)))
+	}
+
+	if tm.Exist(1, 10) {
+		t.Errorf("Value exist but should not")
+	}
+
+	// Ensure key is removed if tree becomes empty
+	tm.Delete(1, 20)
+	if tm.GetKey(1) != nil {
+		t.Errorf("Expected nil for key 1 after deleting all elements, got %v", tm.GetKey(1))
+	}
+
+	// Test deleting a key directly
+	tm.Add(3, 30)
+	tm.DeleteKey(3)
+	if tm.GetKey(3) != nil {
+		t.Errorf("Expected nil for key 3 after deleting the key, got %v", tm.GetKey(3))
+	}
+
+	// Adding a duplicate value should not grow the tree
+	tm.Add(2, 10)
+	if n := len(*tm.GetKey(2)); n != 1 {
+		t.Errorf("Expected 1 element for key 2 after duplicate add, got %d", n)
+	}
+}
+
+func TestTreeMapSortedOrder(t *testing.T) {
+	tm := NewTreeMap[int, int]()
+	values := rand.New(rand.NewSource(1)).Perm(500)
+	for _, v := range values {
+		tm.Add(1, v)
+	}
+
+	got := *tm.GetKey(1)
+	if len(got) != 500 {
+		t.Fatalf("Expected 500 elements, got %d", len(got))
+	}
+	if !sort.IntsAreSorted(got) {
+		t.Errorf("GetKey did not return a sorted slice")
+	}
+
+	if tm.Count() != 500 {
+		t.Errorf("Count() = %d, want 500", tm.Count())
+	}
+
+	// Delete every even value and check the tree stays sorted and consistent
+	for _, v := range values {
+		if v%2 == 0 {
+			tm.Delete(1, v)
+		}
+	}
+	got = *tm.GetKey(1)
+	if len(got) != 250 {
+		t.Fatalf("Expected 250 elements after deleting evens, got %d", len(got))
+	}
+	if !sort.IntsAreSorted(got) {
+		t.Errorf("GetKey did not return a sorted slice after deletions")
+	}
+	for _, v := range got {
+		if v%2 == 0 {
+			t.Errorf("Found even value %d that should have been deleted", v)
+		}
+	}
+}
+
+func TestRangeQueries(t *testing.T) {
+	tm := NewTreeMap[int, int]()
+	for _, v := range []int{10, 20, 30, 40, 50} {
+		tm.Add(1, v)
+	}
+
+	// Range entirely inside the tree
+	if got := tm.GetRange(1, 20, 40); !sort.IntsAreSorted(got) || len(got) != 3 {
+		t.Errorf("GetRange(20, 40) = %v, want [20 30 40]", got)
+	}
+	if n := tm.CountRange(1, 20, 40); n != 3 {
+		t.Errorf("CountRange(20, 40) = %d, want 3", n)
+	}
+
+	// Range covering the entire tree
+	if got := tm.GetRange(1, 0, 100); len(got) != 5 {
+		t.Errorf("GetRange(0, 100) = %v, want all 5 elements", got)
+	}
+
+	// Range outside the tree
+	if got := tm.GetRange(1, 1000, 2000); len(got) != 0 {
+		t.Errorf("GetRange(1000, 2000) = %v, want empty", got)
+	}
+
+	// Range on a missing key
+	if got := tm.GetRange(999, 0, 100); got != nil {
+		t.Errorf("GetRange on missing key = %v, want nil", got)
+	}
+
+	// Empty range (lo > hi)
+	if got := tm.GetRange(1, 40, 20); len(got) != 0 {
+		t.Errorf("GetRange(40, 20) = %v, want empty", got)
+	}
+
+	var visited []int
+	tm.IterateRange(1, 20, 40, func(v int) bool {
+		visited = append(visited, v)
+		return true
+	})
+	if len(visited) != 3 || visited[0] != 20 || visited[2] != 40 {
+		t.Errorf("IterateRange visited = %v, want [20 30 40]", visited)
+	}
+
+	// Delete a range covering the entire tree drops the key
+	n := tm.DeleteRange(1, 0, 100)
+	if n != 5 {
+		t.Errorf("DeleteRange(0, 100) = %d, want 5", n)
+	}
+	if tm.GetKey(1) != nil {
+		t.Errorf("Expected nil for key 1 after DeleteRange removed everything")
+	}
+
+	// DeleteRange on a partial range
+	for _, v := range []int{1, 2, 3, 4, 5} {
+		tm.Add(2, v)
+	}
+	if n := tm.DeleteRange(2, 2, 4); n != 3 {
+		t.Errorf("DeleteRange(2, 4) = %d, want 3", n)
+	}
+	if got := *tm.GetKey(2); len(got) != 2 || got[0] != 1 || got[1] != 5 {
+		t.Errorf("GetKey(2) after DeleteRange = %v, want [1 5]", got)
+	}
+}
+
+func TestRangeQueriesBounds(t *testing.T) {
+	tm := NewTreeMap[int, int]()
+	for _, v := range []int{10, 20, 30, 40, 50} {
+		tm.Add(1, v)
+	}
+
+	// Half-open [lo, hi) excludes the upper bound
+	if got := tm.GetRangeBounds(1, 20, 40, Inclusive, Exclusive); !slices.Equal(got, []int{20, 30}) {
+		t.Errorf("GetRangeBounds(20, 40, Inclusive, Exclusive) = %v, want [20 30]", got)
+	}
+	if n := tm.CountRangeBounds(1, 20, 40, Inclusive, Exclusive); n != 2 {
+		t.Errorf("CountRangeBounds(20, 40, Inclusive, Exclusive) = %d, want 2", n)
+	}
+
+	// Half-open (lo, hi] excludes the lower bound
+	if got := tm.GetRangeBounds(1, 20, 40, Exclusive, Inclusive); !slices.Equal(got, []int{30, 40}) {
+		t.Errorf("GetRangeBounds(20, 40, Exclusive, Inclusive) = %v, want [30 40]", got)
+	}
+
+	// Fully open (lo, hi) excludes both bounds
+	if got := tm.GetRangeBounds(1, 20, 40, Exclusive, Exclusive); !slices.Equal(got, []int{30}) {
+		t.Errorf("GetRangeBounds(20, 40, Exclusive, Exclusive) = %v, want [30]", got)
+	}
+
+	var visited []int
+	tm.IterateRangeBounds(1, 10, 30, Inclusive, Exclusive, func(v int) bool {
+		visited = append(visited, v)
+		return true
+	})
+	if !slices.Equal(visited, []int{10, 20}) {
+		t.Errorf("IterateRangeBounds(10, 30, Inclusive, Exclusive) visited = %v, want [10 20]", visited)
+	}
+
+	// DeleteRangeBounds respects the half-open upper bound
+	for _, v := range []int{1, 2, 3, 4, 5} {
+		tm.Add(2, v)
+	}
+	if n := tm.DeleteRangeBounds(2, 2, 4, Inclusive, Exclusive); n != 2 {
+		t.Errorf("DeleteRangeBounds(2, 4, Inclusive, Exclusive) = %d, want 2", n)
+	}
+	if got := *tm.GetKey(2); !slices.Equal(got, []int{1, 4, 5}) {
+		t.Errorf("GetKey(2) after DeleteRangeBounds = %v, want [1 4 5]", got)
+	}
+}
+
+func TestConcurrency(t *testing.T) {
+	tm := NewTreeMap[int, int]()
+	// Run Add and Delete in parallel
+	go func() {
+		for i := 0; i < 1000; i++ {
+			tm.Add(1, i)
+		}
+	}()
+	go func() {
+		for i := 0; i < 1000; i++ {
+			tm.Delete(1, i)
+		}
+	}()
+
+	// Allow some time for operations to complete
+	t.Parallel()
+}
+
+func BenchmarkTreeMapAdd(b *testing.B) {
+	tm := NewTreeMap[int, int]()
+	for i := 0; i < b.N; i++ {
+		tm.Add(1, i)
+	}
+}
+
+func BenchmarkTreeMapAddDelete(b *testing.B) {
+	tm := NewTreeMap[int, int]()
+	for i := 0; i < b.N; i++ {
+		tm.Add(1, i)
+		tm.Delete(1, i)
+	}
+}
+
+func BenchmarkTreeMapDelete(b *testing.B) {
+	tm := NewTreeMap[int, int]()
+	for i := 0; i < b.N; i++ {
+		tm.Add(1, i)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tm.Delete(1, i)
+	}
+}
+
+// BenchmarkTreeMapAddSequentialN shows the O(log n) cost of Add as the
+// per-key set grows, in contrast to SliceMap's O(n) copy-on-insert
+// (BenchmarkAddSequentialN in the slicemap package, over the same n and
+// insert order so the two are directly comparable). Values are inserted in
+// random order rather than ascending; SliceMap's Add copies the key's
+// whole slice into a new one, whatever the insert position.
+func BenchmarkTreeMapAddSequentialN(b *testing.B) {
+	for _, n := range []int{10, 100, 1_000, 10_000, 100_000} {
+		values := rand.New(rand.NewSource(1)).Perm(n)
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				tm := NewTreeMap[int, int]()
+				for _, v := range values {
+					tm.Add(1, v)
+				}
+			}
+		})
+	}
+}